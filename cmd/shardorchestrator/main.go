@@ -0,0 +1,177 @@
+// Command shardorchestrator spawns and supervises a fleet of venari
+// processes, one per shard, so a single deployment can serve more guilds
+// than a single gateway connection allows.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/exec"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/csmith/envflag"
+)
+
+var (
+	shardCount   = flag.Int("shard-count", 1, "Total number of shards to run")
+	venariPath   = flag.String("venari-path", "venari", "Path to the venari binary to spawn for each shard")
+	listenAddr   = flag.String("listen-address", ":8090", "Address to serve the health/status endpoint on")
+	restartDelay = flag.Duration("restart-delay", 5*time.Second, "Delay before restarting a shard that exits")
+)
+
+func main() {
+	envflag.Parse(envflag.WithPrefix("VENARI_SHARD_"))
+
+	o := newOrchestrator(*shardCount, *venariPath, *restartDelay)
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	var wg sync.WaitGroup
+	for id := 0; id < *shardCount; id++ {
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+			o.run(ctx, id)
+		}(id)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/health", o.handleHealth)
+	mux.HandleFunc("/status", o.handleStatus)
+	server := &http.Server{Addr: *listenAddr, Handler: mux}
+	go func() {
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatalf("Failed to serve health endpoint: %v", err)
+		}
+	}()
+
+	sc := make(chan os.Signal, 1)
+	signal.Notify(sc, syscall.SIGINT, syscall.SIGTERM, os.Interrupt, os.Kill)
+	<-sc
+
+	log.Print("Shutting down shard orchestrator...")
+	cancel()
+	wg.Wait()
+	_ = server.Close()
+}
+
+// shardStatus is the last known state of a supervised shard process.
+type shardStatus struct {
+	ID       int    `json:"id"`
+	Running  bool   `json:"running"`
+	Restarts int    `json:"restarts"`
+	LastExit string `json:"last_exit,omitempty"`
+}
+
+// orchestrator supervises shardCount venari processes, restarting any that
+// exit until the orchestrator itself is shut down.
+type orchestrator struct {
+	shardCount   int
+	venariPath   string
+	restartDelay time.Duration
+
+	mu     sync.Mutex
+	shards map[int]*shardStatus
+}
+
+func newOrchestrator(shardCount int, venariPath string, restartDelay time.Duration) *orchestrator {
+	shards := make(map[int]*shardStatus, shardCount)
+	for id := 0; id < shardCount; id++ {
+		shards[id] = &shardStatus{ID: id}
+	}
+
+	return &orchestrator{
+		shardCount:   shardCount,
+		venariPath:   venariPath,
+		restartDelay: restartDelay,
+		shards:       shards,
+	}
+}
+
+// run starts shard id and restarts it whenever it exits, until ctx is
+// cancelled.
+func (o *orchestrator) run(ctx context.Context, id int) {
+	for ctx.Err() == nil {
+		cmd := exec.CommandContext(
+			ctx,
+			o.venariPath,
+			fmt.Sprintf("--shard-id=%d", id),
+			fmt.Sprintf("--shard-count=%d", o.shardCount),
+		)
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		// exec.CommandContext's default cancellation sends SIGKILL, which
+		// would skip the child's own signal-handling shutdown. Send SIGTERM
+		// instead and only escalate to a hard kill if it doesn't exit promptly.
+		cmd.Cancel = func() error {
+			return cmd.Process.Signal(syscall.SIGTERM)
+		}
+		cmd.WaitDelay = 10 * time.Second
+
+		o.setRunning(id, true)
+		log.Printf("Starting shard %d", id)
+		err := cmd.Run()
+		o.setRunning(id, false)
+		o.recordExit(id, err)
+
+		if ctx.Err() != nil {
+			return
+		}
+
+		log.Printf("Shard %d exited (%v), restarting in %s", id, err, o.restartDelay)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(o.restartDelay):
+		}
+	}
+}
+
+func (o *orchestrator) setRunning(id int, running bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.shards[id].Running = running
+}
+
+func (o *orchestrator) recordExit(id int, err error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.shards[id].Restarts++
+	if err != nil {
+		o.shards[id].LastExit = err.Error()
+	} else {
+		o.shards[id].LastExit = "exited cleanly"
+	}
+}
+
+func (o *orchestrator) handleHealth(w http.ResponseWriter, r *http.Request) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	for _, shard := range o.shards {
+		if !shard.Running {
+			http.Error(w, fmt.Sprintf("shard %d is not running", shard.ID), http.StatusServiceUnavailable)
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte("ok"))
+}
+
+func (o *orchestrator) handleStatus(w http.ResponseWriter, r *http.Request) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(o.shards)
+}
@@ -0,0 +1,143 @@
+// Package cache provides cached channel and role lookups on top of
+// discordgo's session state, falling back to REST calls (and populating
+// the state) on a miss. This avoids repeatedly paging through
+// s.GuildChannels/s.GuildRoles for guilds with large channel or role
+// counts once the state has warmed up.
+//
+// Keeping that state fresh as channels and roles change is handled entirely
+// by discordgo itself (Session.State.OnInterface, driven by
+// TrackChannels/TrackRoles) — this package only adds read-through lookups
+// on top of it.
+package cache
+
+import (
+	"fmt"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Channel returns the channel with the given ID, preferring state and
+// falling back to a REST call (which populates state) on a miss.
+func Channel(s *discordgo.Session, guildID, channelID string) (*discordgo.Channel, error) {
+	if c, err := s.State.Channel(channelID); err == nil {
+		return c, nil
+	}
+
+	c, err := s.Channel(channelID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch channel %s: %w", channelID, err)
+	}
+
+	if err := s.State.ChannelAdd(c); err != nil {
+		return nil, fmt.Errorf("failed to cache channel %s: %w", channelID, err)
+	}
+
+	return c, nil
+}
+
+// ChannelByName finds a channel with the given name and parent within a
+// guild. On a state miss it falls back to a single GuildChannels REST call
+// (Discord doesn't paginate that endpoint; it returns every channel in the
+// guild up to the per-guild channel limit) and populates state from it.
+func ChannelByName(s *discordgo.Session, guildID, parentID, name string) (*discordgo.Channel, error) {
+	if c := findChannel(s, guildID, parentID, name); c != nil {
+		return c, nil
+	}
+
+	if err := refreshChannels(s, guildID); err != nil {
+		return nil, err
+	}
+
+	return findChannel(s, guildID, parentID, name), nil
+}
+
+// CategoryByName finds a category channel with the given name in a guild,
+// or returns nil if none exists.
+func CategoryByName(s *discordgo.Session, guildID, name string) (*discordgo.Channel, error) {
+	if c := findCategory(s, guildID, name); c != nil {
+		return c, nil
+	}
+
+	if err := refreshChannels(s, guildID); err != nil {
+		return nil, err
+	}
+
+	return findCategory(s, guildID, name), nil
+}
+
+// RoleByName finds a role with the given name in a guild, preferring state
+// and falling back to a GuildRoles REST call on a miss.
+func RoleByName(s *discordgo.Session, guildID, name string) (*discordgo.Role, error) {
+	if r := findRole(s, guildID, name); r != nil {
+		return r, nil
+	}
+
+	roles, err := s.GuildRoles(guildID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list roles for guild %s: %w", guildID, err)
+	}
+
+	for _, r := range roles {
+		if err := s.State.RoleAdd(guildID, r); err != nil {
+			return nil, fmt.Errorf("failed to cache role %s: %w", r.ID, err)
+		}
+	}
+
+	return findRole(s, guildID, name), nil
+}
+
+func findChannel(s *discordgo.Session, guildID, parentID, name string) *discordgo.Channel {
+	g, err := s.State.Guild(guildID)
+	if err != nil {
+		return nil
+	}
+
+	for _, c := range g.Channels {
+		if c.Name == name && c.ParentID == parentID {
+			return c
+		}
+	}
+	return nil
+}
+
+func findCategory(s *discordgo.Session, guildID, name string) *discordgo.Channel {
+	g, err := s.State.Guild(guildID)
+	if err != nil {
+		return nil
+	}
+
+	for _, c := range g.Channels {
+		if c.Type == discordgo.ChannelTypeGuildCategory && c.Name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+func findRole(s *discordgo.Session, guildID, name string) *discordgo.Role {
+	g, err := s.State.Guild(guildID)
+	if err != nil {
+		return nil
+	}
+
+	for _, r := range g.Roles {
+		if r.Name == name {
+			return r
+		}
+	}
+	return nil
+}
+
+func refreshChannels(s *discordgo.Session, guildID string) error {
+	channels, err := s.GuildChannels(guildID)
+	if err != nil {
+		return fmt.Errorf("failed to list channels for guild %s: %w", guildID, err)
+	}
+
+	for _, c := range channels {
+		if err := s.State.ChannelAdd(c); err != nil {
+			return fmt.Errorf("failed to cache channel %s: %w", c.ID, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,175 @@
+// Package commandsync reconciles a desired set of slash commands with
+// what's registered with Discord for a guild (or globally), including
+// deleting commands that are no longer wanted, and skips guilds whose
+// commands haven't changed since the last sync to avoid Discord's command
+// update rate limits.
+package commandsync
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"reflect"
+	"syscall"
+
+	"github.com/bwmarrin/discordgo"
+
+	"github.com/csmith/venari/internal/discordretry"
+)
+
+// State maps a guild ID (or "" for global commands) to the hash of the
+// command set that was last successfully synced there.
+type State map[string]string
+
+// LoadState reads sync state from path. A missing file is treated as empty
+// state, since there's nothing to load on first run.
+func LoadState(path string) (State, error) {
+	var state State
+	err := withLock(path, func() error {
+		var err error
+		state, err = loadState(path)
+		return err
+	})
+	return state, err
+}
+
+func loadState(path string) (State, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return State{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read command sync state %s: %w", path, err)
+	}
+
+	var s State
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("failed to parse command sync state %s: %w", path, err)
+	}
+	return s, nil
+}
+
+// SaveGuildHash records the last-synced command hash for a single guild.
+// Deployments can run several shards as separate processes (see
+// cmd/shardorchestrator), each saving entries for the guilds it synced, so
+// this merges the single entry into whatever's currently on disk under a
+// file lock rather than overwriting the whole file with this process's
+// (possibly partial) view of it.
+func SaveGuildHash(path, guildID, hash string) error {
+	return withLock(path, func() error {
+		state, err := loadState(path)
+		if err != nil {
+			return err
+		}
+
+		state[guildID] = hash
+
+		data, err := json.MarshalIndent(state, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode command sync state: %w", err)
+		}
+
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			return fmt.Errorf("failed to write command sync state %s: %w", path, err)
+		}
+		return nil
+	})
+}
+
+// withLock runs fn while holding an exclusive lock on path+".lock", so
+// concurrent readers/writers of the state file (e.g. multiple shards) don't
+// race or observe a half-written file.
+func withLock(path string, fn func() error) error {
+	lock, err := os.OpenFile(path+".lock", os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open command sync lock for %s: %w", path, err)
+	}
+	defer lock.Close()
+
+	if err := syscall.Flock(int(lock.Fd()), syscall.LOCK_EX); err != nil {
+		return fmt.Errorf("failed to lock command sync state %s: %w", path, err)
+	}
+	defer syscall.Flock(int(lock.Fd()), syscall.LOCK_UN)
+
+	return fn()
+}
+
+// hash returns a stable hash of a command set, used to detect whether the
+// desired commands have actually changed since the last sync.
+func hash(commands []*discordgo.ApplicationCommand) (string, error) {
+	data, err := json.Marshal(commands)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash commands: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Sync reconciles commands with what's registered for guildID (empty means
+// global commands): anything missing or changed is created, and anything
+// registered but no longer in commands is deleted. If the hash of commands
+// matches lastHash, the sync is skipped entirely. It returns the hash of
+// commands and whether a sync was actually performed.
+func Sync(s *discordgo.Session, guildID string, commands []*discordgo.ApplicationCommand, lastHash string) (string, bool, error) {
+	sum, err := hash(commands)
+	if err != nil {
+		return "", false, err
+	}
+
+	if lastHash == sum {
+		log.Printf("Commands for guild '%s' unchanged since last sync, skipping", guildID)
+		return sum, false, nil
+	}
+
+	existing, err := s.ApplicationCommands(s.State.User.ID, guildID)
+	if err != nil {
+		return "", false, fmt.Errorf("unable to list commands for guild '%s': %w", guildID, err)
+	}
+
+	for i := range commands {
+		c := commands[i]
+		update := true
+		for j := range existing {
+			e := existing[j]
+			if e.Name == c.Name {
+				update = !reflect.DeepEqual(e.Description, c.Description) || !reflect.DeepEqual(e.Options, c.Options)
+			}
+		}
+
+		if update {
+			log.Printf("Updating command %s for guild '%s'", c.Name, guildID)
+			err := discordretry.Do(func() error {
+				_, err := s.ApplicationCommandCreate(s.State.User.ID, guildID, c)
+				return err
+			})
+			if err != nil {
+				return "", false, fmt.Errorf("cannot create '%s' command for guild '%s': %w", c.Name, guildID, err)
+			}
+		}
+	}
+
+	for _, e := range existing {
+		found := false
+		for _, c := range commands {
+			if c.Name == e.Name {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			log.Printf("Deleting command %s for guild '%s'", e.Name, guildID)
+			err := discordretry.Do(func() error {
+				return s.ApplicationCommandDelete(s.State.User.ID, guildID, e.ID)
+			})
+			if err != nil {
+				return "", false, fmt.Errorf("cannot delete '%s' command for guild '%s': %w", e.Name, guildID, err)
+			}
+		}
+	}
+
+	return sum, true, nil
+}
@@ -0,0 +1,39 @@
+// Package discordretry retries Discord REST calls that fail transiently
+// (rate limiting, 5xx responses) with an exponential backoff, instead of
+// surfacing the error on the first blip.
+package discordretry
+
+import (
+	"errors"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	maxAttempts = 5
+	baseDelay   = 250 * time.Millisecond
+)
+
+// Do calls fn, retrying with exponential backoff if it fails with a
+// rate-limit or server error response from Discord. It gives up and
+// returns the last error after maxAttempts.
+func Do(fn func() error) error {
+	var err error
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		if err = fn(); err == nil || !retryable(err) {
+			return err
+		}
+
+		time.Sleep(baseDelay << attempt)
+	}
+	return err
+}
+
+func retryable(err error) bool {
+	var restErr *discordgo.RESTError
+	if errors.As(err, &restErr) && restErr.Response != nil {
+		return restErr.Response.StatusCode == 429 || restErr.Response.StatusCode >= 500
+	}
+	return false
+}
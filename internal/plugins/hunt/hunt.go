@@ -0,0 +1,503 @@
+// Package hunt is the built-in plugin providing the original hunt/archive/
+// puzzle commands. It's registered the same way any third-party plugin
+// would be, via plugins.Register.
+package hunt
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+
+	"github.com/csmith/venari/internal/cache"
+	"github.com/csmith/venari/internal/discordretry"
+	"github.com/csmith/venari/internal/plugins"
+	"github.com/csmith/venari/internal/storage"
+)
+
+var disallowedCharsRegex = regexp.MustCompile("[^a-zA-Z0-9-]")
+
+// guildOnly is shared by every command below: they all key off a guild
+// channel (hunt/archive categories, per-channel puzzle state), so none of
+// them make sense invoked from a DM.
+var guildOnly = false
+
+// Plugin implements plugins.Plugin for hunt, archive and puzzle management.
+type Plugin struct {
+	store           *storage.Store
+	activeCategory  string
+	archiveCategory string
+}
+
+// New creates the built-in hunt plugin.
+func New(store *storage.Store, activeCategory, archiveCategory string) *Plugin {
+	return &Plugin{
+		store:           store,
+		activeCategory:  activeCategory,
+		archiveCategory: archiveCategory,
+	}
+}
+
+func (p *Plugin) Name() string {
+	return "hunt"
+}
+
+func (p *Plugin) Init(s *discordgo.Session) error {
+	return nil
+}
+
+func (p *Plugin) Commands() []*discordgo.ApplicationCommand {
+	return []*discordgo.ApplicationCommand{
+		{
+			Name:         "hunt",
+			Description:  "Manage puzzle hunts",
+			DMPermission: &guildOnly,
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "create",
+					Description: "Create a new hunt with the given name",
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Type:        discordgo.ApplicationCommandOptionString,
+							Name:        "name",
+							Description: "Name of the puzzle hunt",
+							Required:    true,
+						},
+					},
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "list",
+					Description: "List active hunts in this server",
+				},
+			},
+		},
+		{
+			Name:         "archive",
+			Description:  "Archives a hunt",
+			DMPermission: &guildOnly,
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionChannel,
+					Name:        "channel",
+					Description: "Channel to be archived",
+					Required:    true,
+				},
+			},
+		},
+		{
+			Name:         "puzzle",
+			Description:  "Manage puzzles within a hunt",
+			DMPermission: &guildOnly,
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "add",
+					Description: "Register a new puzzle in this hunt channel",
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Type:        discordgo.ApplicationCommandOptionString,
+							Name:        "name",
+							Description: "Name of the puzzle",
+							Required:    true,
+						},
+					},
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "solve",
+					Description: "Mark a puzzle as solved",
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Type:        discordgo.ApplicationCommandOptionString,
+							Name:        "name",
+							Description: "Name of the puzzle",
+							Required:    true,
+						},
+						{
+							Type:        discordgo.ApplicationCommandOptionString,
+							Name:        "answer",
+							Description: "The solution to the puzzle",
+							Required:    true,
+						},
+					},
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "status",
+					Description: "Show the puzzle leaderboard for this hunt",
+				},
+			},
+		},
+	}
+}
+
+func (p *Plugin) Handle(s *discordgo.Session, i *discordgo.InteractionCreate) bool {
+	payload := i.Interaction.Data.(discordgo.ApplicationCommandInteractionData)
+
+	switch payload.Name {
+	case "hunt":
+		plugins.Async(s, i, func(respond func(string)) {
+			sub := payload.Options[0]
+			switch sub.Name {
+			case "create":
+				p.createHunt(s, i.GuildID, respond, sub.Options[0].StringValue())
+			case "list":
+				p.listHunts(i.GuildID, respond)
+			}
+		})
+		return true
+	case "archive":
+		plugins.Async(s, i, func(respond func(string)) {
+			p.archiveHunt(s, i.GuildID, respond, payload.Options[0].ChannelValue(s))
+		})
+		return true
+	case "puzzle":
+		plugins.Async(s, i, func(respond func(string)) {
+			sub := payload.Options[0]
+			switch sub.Name {
+			case "add":
+				p.addPuzzle(i.GuildID, i.ChannelID, respond, sub.Options[0].StringValue())
+			case "solve":
+				p.solvePuzzle(i.GuildID, i.ChannelID, respond, i.Member.User.Username, sub.Options[0].StringValue(), sub.Options[1].StringValue())
+			case "status":
+				p.puzzleStatus(i.GuildID, i.ChannelID, respond)
+			}
+		})
+		return true
+	}
+
+	return false
+}
+
+func (p *Plugin) createHunt(s *discordgo.Session, guildId string, respond func(message string), name string) {
+	normalised := disallowedCharsRegex.ReplaceAllString(strings.ReplaceAll(strings.ToLower(name), " ", "-"), "")
+	roleName := fmt.Sprintf("hunt-%s", normalised)
+
+	category, err := p.findCategory(s, guildId, p.activeCategory)
+	if err != nil {
+		plugins.RespondErr(respond, err)
+		return
+	}
+
+	role, err := s.GuildRoleCreate(
+		guildId,
+		&discordgo.RoleParams{
+			Name: roleName,
+		},
+	)
+	if err != nil {
+		plugins.RespondErr(respond, plugins.Wrapf(err, "failed to create role %s in guild %s", roleName, guildId))
+		return
+	}
+
+	var channel *discordgo.Channel
+	err = discordretry.Do(func() error {
+		channel, err = s.GuildChannelCreateComplex(guildId, discordgo.GuildChannelCreateData{
+			Type:     discordgo.ChannelTypeGuildText,
+			Name:     normalised,
+			ParentID: category.ID,
+			PermissionOverwrites: []*discordgo.PermissionOverwrite{
+				{
+					ID:    role.ID,
+					Type:  discordgo.PermissionOverwriteTypeRole,
+					Allow: discordgo.PermissionAllText,
+				},
+				{
+					ID:   guildId,
+					Type: discordgo.PermissionOverwriteTypeRole,
+					Deny: discordgo.PermissionAll,
+				},
+			},
+		})
+		return err
+	})
+	if err != nil {
+		plugins.RespondErr(respond, plugins.Wrapf(err, "failed to create text channel %s in guild %s", normalised, guildId))
+		return
+	}
+
+	err = discordretry.Do(func() error {
+		_, err := s.GuildChannelCreateComplex(guildId, discordgo.GuildChannelCreateData{
+			Type:     discordgo.ChannelTypeGuildVoice,
+			Name:     normalised,
+			ParentID: category.ID,
+			PermissionOverwrites: []*discordgo.PermissionOverwrite{
+				{
+					ID:    role.ID,
+					Type:  discordgo.PermissionOverwriteTypeRole,
+					Allow: discordgo.PermissionAllVoice,
+				},
+				{
+					ID:   guildId,
+					Type: discordgo.PermissionOverwriteTypeRole,
+					Deny: discordgo.PermissionAll,
+				},
+			},
+		})
+		return err
+	})
+	if err != nil {
+		plugins.RespondErr(respond, plugins.Wrapf(err, "failed to create voice channel %s in guild %s", normalised, guildId))
+		return
+	}
+
+	if _, err := p.store.CreateHunt(guildId, channel.ID, role.ID, normalised); err != nil {
+		plugins.RespondErr(respond, plugins.Wrapf(err, "failed to record hunt %s in storage", normalised))
+		return
+	}
+
+	respond(fmt.Sprintf("Hunt created: %s", normalised))
+}
+
+func (p *Plugin) listHunts(guildId string, respond func(message string)) {
+	hunts, err := p.store.Hunts(guildId, false)
+	if err != nil {
+		plugins.RespondErr(respond, plugins.Wrapf(err, "failed to list hunts for guild %s", guildId))
+		return
+	}
+
+	if len(hunts) == 0 {
+		respond("No active hunts.")
+		return
+	}
+
+	var b strings.Builder
+	b.WriteString("Active hunts:\n")
+	for _, h := range hunts {
+		fmt.Fprintf(&b, "- %s (<#%s>)\n", h.Name, h.ChannelID)
+	}
+	respond(b.String())
+}
+
+func (p *Plugin) archiveHunt(s *discordgo.Session, guildId string, respond func(message string), target *discordgo.Channel) {
+	log.Printf("Archive request for channel %s in guild %s", target.Name, guildId)
+	channelID := target.ID
+
+	// Re-resolve via the cache rather than trusting the channel object
+	// handed to us by the interaction payload, so a stale ParentID (e.g.
+	// the channel was just moved) doesn't let an archived channel slip past
+	// the check below.
+	target, err := cache.Channel(s, guildId, channelID)
+	if err != nil {
+		plugins.RespondErr(respond, plugins.Wrapf(err, "failed to look up channel %s in guild %s", channelID, guildId))
+		return
+	}
+
+	active, err := p.findCategory(s, guildId, p.activeCategory)
+	if err != nil {
+		plugins.RespondErr(respond, err)
+		return
+	}
+
+	archive, err := p.findCategory(s, guildId, p.archiveCategory)
+	if err != nil {
+		plugins.RespondErr(respond, err)
+		return
+	}
+
+	if target.ParentID != active.ID {
+		respond("That channel doesn't seem to be an active hunt channel. Do better.")
+		return
+	}
+
+	h, err := p.store.HuntByChannel(guildId, target.ID)
+	if err != nil {
+		plugins.RespondErr(respond, plugins.Wrapf(err, "failed to look up hunt for channel %s", target.ID))
+		return
+	}
+
+	c, err := cache.ChannelByName(s, guildId, archive.ID, target.Name)
+	if err != nil {
+		plugins.RespondErr(respond, plugins.Wrapf(err, "failed to look up channel %s in guild %s", target.Name, guildId))
+		return
+	}
+
+	if c != nil {
+		if c.Type == discordgo.ChannelTypeGuildVoice {
+			log.Printf("Deleting voice channel %s", c.Name)
+			err := discordretry.Do(func() error {
+				_, err := s.ChannelDelete(c.ID)
+				return err
+			})
+			if err != nil {
+				plugins.RespondErr(respond, plugins.Wrapf(err, "failed to delete voice channel %s in guild %s", target.Name, guildId))
+				return
+			}
+		} else if c.Type == discordgo.ChannelTypeGuildText {
+			log.Printf("Deleting text channel %s", c.Name)
+			err := discordretry.Do(func() error {
+				_, err := s.ChannelEdit(c.ID, &discordgo.ChannelEdit{
+					Name:                 fmt.Sprintf("%s-%s", time.Now().Format("2006-01"), c.Name),
+					ParentID:             archive.ID,
+					PermissionOverwrites: archive.PermissionOverwrites,
+				})
+				return err
+			})
+			if err != nil {
+				plugins.RespondErr(respond, plugins.Wrapf(err, "failed to edit text channel %s in guild %s", target.Name, guildId))
+				return
+			}
+		}
+	}
+
+	// Mark the hunt archived in storage now, independently of the role
+	// deletion below: the channels are already moved at this point, so if
+	// role deletion fails we'd rather be left with a stray role than with
+	// storage still reporting an archived-looking hunt as active (which
+	// would also make /archive unusable against it a second time, since the
+	// channel has already moved out of the active category).
+	if h != nil {
+		if err := p.store.ArchiveHunt(h.ID); err != nil {
+			plugins.RespondErr(respond, plugins.Wrapf(err, "failed to mark hunt %d archived", h.ID))
+			return
+		}
+	}
+
+	roleID := ""
+	if h != nil {
+		roleID = h.RoleID
+	} else {
+		// The hunt predates storage tracking; fall back to the old
+		// name-based convention so pre-existing hunts still archive cleanly.
+		role, err := cache.RoleByName(s, guildId, fmt.Sprintf("hunt-%s", target.Name))
+		if err != nil {
+			plugins.RespondErr(respond, plugins.Wrapf(err, "failed to look up role for hunt %s", target.Name))
+			return
+		}
+		if role != nil {
+			roleID = role.ID
+		}
+	}
+
+	if roleID != "" {
+		log.Printf("Deleting role %s", roleID)
+		if err := s.GuildRoleDelete(guildId, roleID); err != nil {
+			plugins.RespondErr(respond, plugins.Wrapf(err, "failed to delete role %s in guild %s", roleID, guildId))
+			return
+		}
+	}
+
+	respond(fmt.Sprintf("Hunt archived"))
+}
+
+func (p *Plugin) addPuzzle(guildId, channelId string, respond func(message string), name string) {
+	h, err := p.store.HuntByChannel(guildId, channelId)
+	if err != nil {
+		plugins.RespondErr(respond, plugins.Wrapf(err, "failed to look up hunt for channel %s", channelId))
+		return
+	}
+	if h == nil {
+		respond("This channel isn't a hunt channel.")
+		return
+	}
+
+	if _, err := p.store.AddPuzzle(h.ID, name); err != nil {
+		plugins.RespondErr(respond, plugins.Wrapf(err, "failed to add puzzle %s to hunt %d", name, h.ID))
+		return
+	}
+
+	respond(fmt.Sprintf("Puzzle added: %s", name))
+}
+
+func (p *Plugin) solvePuzzle(guildId, channelId string, respond func(message string), solver, name, answer string) {
+	h, err := p.store.HuntByChannel(guildId, channelId)
+	if err != nil {
+		plugins.RespondErr(respond, plugins.Wrapf(err, "failed to look up hunt for channel %s", channelId))
+		return
+	}
+	if h == nil {
+		respond("This channel isn't a hunt channel.")
+		return
+	}
+
+	puzzle, err := p.store.PuzzleByName(h.ID, name)
+	if err != nil {
+		plugins.RespondErr(respond, plugins.Wrapf(err, "failed to look up puzzle %s in hunt %d", name, h.ID))
+		return
+	}
+	if puzzle == nil {
+		respond(fmt.Sprintf("No such puzzle: %s", name))
+		return
+	}
+
+	if err := p.store.SolvePuzzle(puzzle.ID, solver, answer); err != nil {
+		plugins.RespondErr(respond, plugins.Wrapf(err, "failed to mark puzzle %d solved", puzzle.ID))
+		return
+	}
+
+	respond(fmt.Sprintf("%s solved by %s! Answer: %s", name, solver, answer))
+}
+
+func (p *Plugin) puzzleStatus(guildId, channelId string, respond func(message string)) {
+	h, err := p.store.HuntByChannel(guildId, channelId)
+	if err != nil {
+		plugins.RespondErr(respond, plugins.Wrapf(err, "failed to look up hunt for channel %s", channelId))
+		return
+	}
+	if h == nil {
+		respond("This channel isn't a hunt channel.")
+		return
+	}
+
+	puzzles, err := p.store.Puzzles(h.ID)
+	if err != nil {
+		plugins.RespondErr(respond, plugins.Wrapf(err, "failed to list puzzles for hunt %d", h.ID))
+		return
+	}
+
+	leaderboard, err := p.store.Leaderboard(h.ID)
+	if err != nil {
+		plugins.RespondErr(respond, plugins.Wrapf(err, "failed to build leaderboard for hunt %d", h.ID))
+		return
+	}
+
+	solved := 0
+	var b strings.Builder
+	b.WriteString("Puzzles:\n")
+	for _, puzzle := range puzzles {
+		if puzzle.Solved {
+			solved++
+			fmt.Fprintf(&b, "- %s ✅ (%s, %s)\n", puzzle.Name, puzzle.SolvedBy, puzzle.Answer)
+		} else {
+			fmt.Fprintf(&b, "- %s\n", puzzle.Name)
+		}
+	}
+	fmt.Fprintf(&b, "\n%d/%d solved\n", solved, len(puzzles))
+
+	if len(leaderboard) > 0 {
+		b.WriteString("\nLeaderboard:\n")
+		for _, e := range leaderboard {
+			fmt.Fprintf(&b, "- %s: %d\n", e.Solver, e.Solves)
+		}
+	}
+
+	respond(b.String())
+}
+
+func (p *Plugin) findCategory(s *discordgo.Session, guildId string, categoryName string) (*discordgo.Channel, error) {
+	c, err := cache.CategoryByName(s, guildId, categoryName)
+	if err != nil {
+		return nil, plugins.Wrapf(err, "failed to look up category %s in guild %s", categoryName, guildId)
+	}
+	if c != nil {
+		return c, nil
+	}
+
+	err = discordretry.Do(func() error {
+		var err error
+		c, err = s.GuildChannelCreateComplex(guildId, discordgo.GuildChannelCreateData{
+			Type: discordgo.ChannelTypeGuildCategory,
+			Name: categoryName,
+		})
+		return err
+	})
+	if err != nil {
+		return nil, plugins.Wrapf(err, "failed to create category %s in guild %s", categoryName, guildId)
+	}
+	return c, nil
+}
@@ -0,0 +1,36 @@
+// Package plugins defines the extension point used to add new slash
+// commands to Venari without editing the main command dispatch loop.
+package plugins
+
+import "github.com/bwmarrin/discordgo"
+
+// Plugin is a self-contained set of slash commands and their handlers.
+// Built-in functionality (hunts, puzzles) is implemented as a Plugin in
+// exactly the same way a third-party extension would be.
+type Plugin interface {
+	// Name identifies the plugin, for logging purposes.
+	Name() string
+
+	// Commands returns the application commands this plugin owns. They're
+	// aggregated across all registered plugins and synced with Discord.
+	Commands() []*discordgo.ApplicationCommand
+
+	// Init is called once, after the session is open, so the plugin can
+	// perform any setup that requires a live connection.
+	Init(s *discordgo.Session) error
+
+	// Handle is invoked for every application command interaction (other
+	// interaction types are filtered out before dispatch). It returns true if the
+	// plugin owns the interaction's command and has handled it, or false
+	// if the interaction should be offered to the next registered plugin.
+	Handle(s *discordgo.Session, i *discordgo.InteractionCreate) bool
+}
+
+// Registered holds every plugin active in this build, in registration order.
+var Registered []Plugin
+
+// Register adds a plugin to the set that will be initialised and have its
+// commands synced and interactions dispatched to it.
+func Register(p Plugin) {
+	Registered = append(Registered, p)
+}
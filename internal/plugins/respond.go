@@ -0,0 +1,81 @@
+package plugins
+
+import (
+	"fmt"
+	"log"
+	"runtime/debug"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// maxPanicMessageLen bounds how much of a captured stack trace gets posted
+// back to Discord; messages are capped at 2000 characters anyway.
+const maxPanicMessageLen = 1500
+
+// Async acknowledges the interaction immediately (Discord requires a
+// response within 3 seconds) and then runs fn in the background, giving it
+// a respond function that posts the eventual result as a followup message.
+// Plugins use this instead of responding to interactions directly so that
+// slow handlers (database queries, Discord API calls) don't block the
+// gateway's event loop.
+//
+// If fn panics, the panic is recovered, logged with a stack trace, and a
+// truncated error message is posted to the channel the interaction came
+// from so the bot doesn't just go silent.
+func Async(s *discordgo.Session, i *discordgo.InteractionCreate, fn func(respond func(message string))) {
+	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+	})
+	if err != nil {
+		log.Printf("Failed to ACK command: %v", err)
+	}
+
+	go func() {
+		defer capturePanic(s, i)
+
+		respond := func(message string) {
+			_, err := s.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
+				Content: message,
+			})
+
+			if err != nil {
+				log.Printf("Unable to send command response: %v", err)
+			}
+		}
+
+		fn(respond)
+	}()
+}
+
+func capturePanic(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	r := recover()
+	if r == nil {
+		return
+	}
+
+	stack := string(debug.Stack())
+	log.Printf("Recovered panic handling interaction: %v\n%s", r, stack)
+
+	message := fmt.Sprintf("Something went badly wrong handling that command:\n```\n%v\n```", r)
+	if len(message) > maxPanicMessageLen {
+		message = message[:maxPanicMessageLen] + "...\n```"
+	}
+
+	if _, err := s.ChannelMessageSend(i.ChannelID, message); err != nil {
+		log.Printf("Failed to report panic to channel %s: %v", i.ChannelID, err)
+	}
+}
+
+// RespondErr logs err (wrapped with context via Wrapf at the call site) and
+// reports a short, user-facing message back via respond. It's the standard
+// way to handle a failed Discord API call inside a plugin: log for the
+// operator, tell the user, and return rather than crashing the process.
+func RespondErr(respond func(message string), err error) {
+	log.Printf("Error handling command: %v", err)
+	respond(fmt.Sprintf("Sorry, something went wrong: %v", err))
+}
+
+// Wrapf wraps err with additional context, in the style of fmt.Errorf("...: %w", err).
+func Wrapf(err error, format string, args ...interface{}) error {
+	return fmt.Errorf(format+": %w", append(args, err)...)
+}
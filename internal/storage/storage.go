@@ -0,0 +1,267 @@
+// Package storage provides persistent tracking of hunts, puzzles and solves
+// so that state survives bot restarts instead of being derived on the fly
+// from Discord channel and role names.
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// Hunt represents a single puzzle hunt, backed by a Discord text/voice
+// channel pair and a role used to grant access to them.
+type Hunt struct {
+	ID        int64
+	GuildID   string
+	ChannelID string
+	RoleID    string
+	Name      string
+	Archived  bool
+}
+
+// Puzzle represents a single puzzle registered within a hunt.
+type Puzzle struct {
+	ID       int64
+	HuntID   int64
+	Name     string
+	Answer   string
+	Solved   bool
+	SolvedBy string
+	SolvedAt time.Time
+}
+
+// LeaderboardEntry summarises how many puzzles a solver has solved within a hunt.
+type LeaderboardEntry struct {
+	Solver string
+	Solves int
+}
+
+// Store wraps a SQLite-backed database holding hunt and puzzle state.
+type Store struct {
+	db *sql.DB
+}
+
+// Open opens (creating if necessary) the SQLite database at path and
+// ensures the schema is up to date.
+//
+// Every interaction is handled on its own goroutine (see plugins.Async), so
+// concurrent writers are the norm rather than the exception: a busy timeout
+// and WAL journal mode keep a second writer waiting for a lock instead of
+// failing immediately with SQLITE_BUSY.
+func Open(path string) (*Store, error) {
+	db, err := sql.Open("sqlite", fmt.Sprintf("%s?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)", path))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	s := &Store{db: db}
+	if err := s.migrate(); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("failed to migrate database: %w", err)
+	}
+
+	return s, nil
+}
+
+// Close closes the underlying database.
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+func (s *Store) migrate() error {
+	_, err := s.db.Exec(`
+		CREATE TABLE IF NOT EXISTS hunts (
+			id          INTEGER PRIMARY KEY AUTOINCREMENT,
+			guild_id    TEXT NOT NULL,
+			channel_id  TEXT NOT NULL,
+			role_id     TEXT NOT NULL,
+			name        TEXT NOT NULL,
+			archived    INTEGER NOT NULL DEFAULT 0
+		);
+
+		CREATE TABLE IF NOT EXISTS puzzles (
+			id          INTEGER PRIMARY KEY AUTOINCREMENT,
+			hunt_id     INTEGER NOT NULL REFERENCES hunts(id),
+			name        TEXT NOT NULL,
+			answer      TEXT NOT NULL DEFAULT '',
+			solved_by   TEXT NOT NULL DEFAULT '',
+			solved_at   DATETIME
+		);
+	`)
+	return err
+}
+
+// CreateHunt records a new hunt and returns its stored representation.
+func (s *Store) CreateHunt(guildID, channelID, roleID, name string) (*Hunt, error) {
+	res, err := s.db.Exec(
+		`INSERT INTO hunts (guild_id, channel_id, role_id, name) VALUES (?, ?, ?, ?)`,
+		guildID, channelID, roleID, name,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert hunt: %w", err)
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read new hunt id: %w", err)
+	}
+
+	return &Hunt{ID: id, GuildID: guildID, ChannelID: channelID, RoleID: roleID, Name: name}, nil
+}
+
+// HuntByChannel finds the hunt associated with the given channel, if any.
+func (s *Store) HuntByChannel(guildID, channelID string) (*Hunt, error) {
+	h := &Hunt{}
+	var archived int
+	err := s.db.QueryRow(
+		`SELECT id, guild_id, channel_id, role_id, name, archived FROM hunts WHERE guild_id = ? AND channel_id = ?`,
+		guildID, channelID,
+	).Scan(&h.ID, &h.GuildID, &h.ChannelID, &h.RoleID, &h.Name, &archived)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up hunt for channel %s: %w", channelID, err)
+	}
+	h.Archived = archived != 0
+	return h, nil
+}
+
+// ArchiveHunt marks a hunt as archived.
+func (s *Store) ArchiveHunt(huntID int64) error {
+	_, err := s.db.Exec(`UPDATE hunts SET archived = 1 WHERE id = ?`, huntID)
+	if err != nil {
+		return fmt.Errorf("failed to archive hunt %d: %w", huntID, err)
+	}
+	return nil
+}
+
+// Hunts returns every hunt in the given guild, optionally including archived ones.
+func (s *Store) Hunts(guildID string, includeArchived bool) ([]*Hunt, error) {
+	query := `SELECT id, guild_id, channel_id, role_id, name, archived FROM hunts WHERE guild_id = ?`
+	if !includeArchived {
+		query += ` AND archived = 0`
+	}
+
+	rows, err := s.db.Query(query, guildID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list hunts for guild %s: %w", guildID, err)
+	}
+	defer rows.Close()
+
+	var hunts []*Hunt
+	for rows.Next() {
+		h := &Hunt{}
+		var archived int
+		if err := rows.Scan(&h.ID, &h.GuildID, &h.ChannelID, &h.RoleID, &h.Name, &archived); err != nil {
+			return nil, fmt.Errorf("failed to scan hunt row: %w", err)
+		}
+		h.Archived = archived != 0
+		hunts = append(hunts, h)
+	}
+	return hunts, rows.Err()
+}
+
+// AddPuzzle registers a new puzzle within a hunt.
+func (s *Store) AddPuzzle(huntID int64, name string) (*Puzzle, error) {
+	res, err := s.db.Exec(`INSERT INTO puzzles (hunt_id, name) VALUES (?, ?)`, huntID, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert puzzle: %w", err)
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read new puzzle id: %w", err)
+	}
+
+	return &Puzzle{ID: id, HuntID: huntID, Name: name}, nil
+}
+
+// PuzzleByName finds a puzzle within a hunt by its (case-sensitive) name.
+func (s *Store) PuzzleByName(huntID int64, name string) (*Puzzle, error) {
+	p := &Puzzle{}
+	var solvedBy string
+	var solvedAt sql.NullTime
+	err := s.db.QueryRow(
+		`SELECT id, hunt_id, name, answer, solved_by, solved_at FROM puzzles WHERE hunt_id = ? AND name = ?`,
+		huntID, name,
+	).Scan(&p.ID, &p.HuntID, &p.Name, &p.Answer, &solvedBy, &solvedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up puzzle %q: %w", name, err)
+	}
+	p.SolvedBy = solvedBy
+	p.Solved = solvedBy != ""
+	if solvedAt.Valid {
+		p.SolvedAt = solvedAt.Time
+	}
+	return p, nil
+}
+
+// SolvePuzzle marks a puzzle as solved with the given answer and solver.
+func (s *Store) SolvePuzzle(puzzleID int64, solver, answer string) error {
+	_, err := s.db.Exec(
+		`UPDATE puzzles SET answer = ?, solved_by = ?, solved_at = ? WHERE id = ?`,
+		answer, solver, time.Now(), puzzleID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to mark puzzle %d solved: %w", puzzleID, err)
+	}
+	return nil
+}
+
+// Puzzles returns every puzzle registered within a hunt.
+func (s *Store) Puzzles(huntID int64) ([]*Puzzle, error) {
+	rows, err := s.db.Query(
+		`SELECT id, hunt_id, name, answer, solved_by, solved_at FROM puzzles WHERE hunt_id = ? ORDER BY id`,
+		huntID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list puzzles for hunt %d: %w", huntID, err)
+	}
+	defer rows.Close()
+
+	var puzzles []*Puzzle
+	for rows.Next() {
+		p := &Puzzle{}
+		var solvedBy string
+		var solvedAt sql.NullTime
+		if err := rows.Scan(&p.ID, &p.HuntID, &p.Name, &p.Answer, &solvedBy, &solvedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan puzzle row: %w", err)
+		}
+		p.SolvedBy = solvedBy
+		p.Solved = solvedBy != ""
+		if solvedAt.Valid {
+			p.SolvedAt = solvedAt.Time
+		}
+		puzzles = append(puzzles, p)
+	}
+	return puzzles, rows.Err()
+}
+
+// Leaderboard returns solve counts per solver for a hunt, ordered by most solves first.
+func (s *Store) Leaderboard(huntID int64) ([]LeaderboardEntry, error) {
+	rows, err := s.db.Query(
+		`SELECT solved_by, COUNT(*) FROM puzzles WHERE hunt_id = ? AND solved_by != '' GROUP BY solved_by ORDER BY COUNT(*) DESC`,
+		huntID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build leaderboard for hunt %d: %w", huntID, err)
+	}
+	defer rows.Close()
+
+	var entries []LeaderboardEntry
+	for rows.Next() {
+		var e LeaderboardEntry
+		if err := rows.Scan(&e.Solver, &e.Solves); err != nil {
+			return nil, fmt.Errorf("failed to scan leaderboard row: %w", err)
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}